@@ -0,0 +1,132 @@
+// Package crawlstate persists crawl progress to an on-disk bbolt
+// database so an interrupted crawl can resume with -resume instead of
+// re-fetching pages it already completed.
+package crawlstate
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var urlsBucket = []byte("urls")
+
+// Status records the fetch outcome for a single URL.
+type Status int
+
+const (
+	// Pending marks a URL that has been discovered but not yet fetched.
+	Pending Status = iota
+	// Done marks a URL that was fetched and processed successfully.
+	Done
+	// Failed marks a URL whose fetch did not complete.
+	Failed
+)
+
+// Record is everything persisted for one URL: not just whether it was
+// fetched, but the sitemap metadata recorded for it, so a resumed run
+// can reconstruct the final sitemap without re-fetching it.
+type Record struct {
+	Status       Status
+	Loc          string
+	LastModified time.Time
+	ChangeFreq   string
+	Priority     string
+}
+
+// Store wraps a bbolt database recording, per URL, its fetch status
+// and sitemap metadata from a prior run.
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens (creating if necessary) the crawl-state database in dir.
+func Open(dir string) (*Store, error) {
+	db, err := bolt.Open(filepath.Join(dir, "crawlstate.db"), 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(urlsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the underlying database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// IsDone reports whether url was already fetched successfully in a
+// previous run.
+func (s *Store) IsDone(url string) bool {
+	rec, ok := s.get(url)
+	return ok && rec.Status == Done
+}
+
+func (s *Store) get(url string) (Record, bool) {
+	var rec Record
+	var ok bool
+	_ = s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(urlsBucket).Get([]byte(url))
+		if v == nil {
+			return nil
+		}
+		ok = json.Unmarshal(v, &rec) == nil
+		return nil
+	})
+	return rec, ok
+}
+
+// Mark records url's fetch outcome with no sitemap metadata, for URLs
+// that never reached a successful fetch. Use MarkDone to additionally
+// persist the metadata a successful fetch produced.
+func (s *Store) Mark(url string, status Status) error {
+	return s.put(url, Record{Status: status, Loc: url})
+}
+
+// MarkDone records url as fetched successfully along with the sitemap
+// metadata recorded for it, so a later -resume run can restore it into
+// the sitemap without re-fetching url.
+func (s *Store) MarkDone(url string, rec Record) error {
+	rec.Status = Done
+	rec.Loc = url
+	return s.put(url, rec)
+}
+
+func (s *Store) put(url string, rec Record) error {
+	v, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(urlsBucket).Put([]byte(url), v)
+	})
+}
+
+// DoneRecords returns the sitemap metadata recorded for every URL
+// marked Done in a prior run, so -resume can repopulate the crawler's
+// seen set without re-fetching them.
+func (s *Store) DoneRecords() (map[string]Record, error) {
+	records := make(map[string]Record)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(urlsBucket).ForEach(func(k, v []byte) error {
+			var rec Record
+			if err := json.Unmarshal(v, &rec); err != nil {
+				return nil // skip a corrupt entry rather than failing the whole resume
+			}
+			if rec.Status == Done {
+				records[string(k)] = rec
+			}
+			return nil
+		})
+	})
+	return records, err
+}
@@ -0,0 +1,96 @@
+package crawlstate
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMarkDoneAndIsDone(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+
+	if s.IsDone("https://example.com/") {
+		t.Fatal("unseen URL reported done")
+	}
+
+	lastMod := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	rec := Record{LastModified: lastMod, ChangeFreq: "daily", Priority: "0.8"}
+	if err := s.MarkDone("https://example.com/", rec); err != nil {
+		t.Fatalf("MarkDone: %v", err)
+	}
+	if !s.IsDone("https://example.com/") {
+		t.Fatal("marked URL not reported done")
+	}
+}
+
+func TestDoneRecordsOmitsPendingAndFailed(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+
+	if err := s.MarkDone("https://example.com/done", Record{ChangeFreq: "weekly"}); err != nil {
+		t.Fatalf("MarkDone: %v", err)
+	}
+	if err := s.Mark("https://example.com/failed", Failed); err != nil {
+		t.Fatalf("Mark: %v", err)
+	}
+	if err := s.Mark("https://example.com/pending", Pending); err != nil {
+		t.Fatalf("Mark: %v", err)
+	}
+
+	records, err := s.DoneRecords()
+	if err != nil {
+		t.Fatalf("DoneRecords: %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("got %d done records, want 1", len(records))
+	}
+	rec, ok := records["https://example.com/done"]
+	if !ok {
+		t.Fatal("done URL missing from DoneRecords")
+	}
+	if rec.ChangeFreq != "weekly" {
+		t.Errorf("ChangeFreq = %q, want %q", rec.ChangeFreq, "weekly")
+	}
+}
+
+// TestDoneRecordsSurvivesReopen simulates a -resume run: the state
+// database is closed (as if the process were interrupted) and reopened
+// from the same directory, and the Done records recorded before the
+// interruption must still be there.
+func TestDoneRecordsSurvivesReopen(t *testing.T) {
+	dir := t.TempDir()
+
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := s.MarkDone("https://example.com/a", Record{ChangeFreq: "monthly"}); err != nil {
+		t.Fatalf("MarkDone: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	resumed, err := Open(dir)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer resumed.Close()
+
+	if !resumed.IsDone("https://example.com/a") {
+		t.Fatal("Done URL lost across reopen")
+	}
+	records, err := resumed.DoneRecords()
+	if err != nil {
+		t.Fatalf("DoneRecords: %v", err)
+	}
+	if rec, ok := records["https://example.com/a"]; !ok || rec.ChangeFreq != "monthly" {
+		t.Errorf("DoneRecords after reopen = %+v, want ChangeFreq=monthly", records)
+	}
+}
@@ -0,0 +1,177 @@
+// Package hostlimiter gates outbound fetches per-host so a single busy
+// site can't monopolize the crawler's worker pool: each host gets its
+// own token-bucket rate limit and a bounded number of concurrent
+// in-flight requests.
+package hostlimiter
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	backoffBase = 500 * time.Millisecond
+	backoffCap  = 30 * time.Second
+
+	// penaltyDuration is how long a host's rate stays halved after a
+	// 429/503 response.
+	penaltyDuration = time.Minute
+)
+
+// Limiter hands out per-host rate/concurrency gates, created lazily on
+// first use.
+type Limiter struct {
+	rps                float64
+	burst              int
+	perHostConcurrency int
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+type hostState struct {
+	limiter *rate.Limiter
+	sem     chan struct{}
+
+	mu            sync.Mutex
+	penalizedTil  time.Time
+	rateRestoreAt time.Time   // when the halved rate should return to normal
+	restoreTimer  *time.Timer // single timer driving rateRestoreAt, reset rather than stacked
+}
+
+// New returns a Limiter granting each host up to rps requests/sec
+// (with the given burst) and at most perHostConcurrency requests in
+// flight at once.
+func New(rps float64, burst, perHostConcurrency int) *Limiter {
+	return &Limiter{
+		rps:                rps,
+		burst:              burst,
+		perHostConcurrency: perHostConcurrency,
+		hosts:              make(map[string]*hostState),
+	}
+}
+
+func (l *Limiter) stateFor(host string) *hostState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	hs, ok := l.hosts[host]
+	if !ok {
+		hs = &hostState{
+			limiter: rate.NewLimiter(rate.Limit(l.rps), l.burst),
+			sem:     make(chan struct{}, l.perHostConcurrency),
+		}
+		l.hosts[host] = hs
+	}
+	return hs
+}
+
+// Acquire blocks until host has both rate-limiter and concurrency
+// headroom for one request, honoring any active 429/503 penalty.
+// Callers must call the returned release func exactly once.
+func (l *Limiter) Acquire(ctx context.Context, host string) (release func(), err error) {
+	hs := l.stateFor(host)
+
+	if err := sleepUntilUnpenalized(ctx, hs); err != nil {
+		return nil, err
+	}
+	if err := hs.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	select {
+	case hs.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	return func() { <-hs.sem }, nil
+}
+
+func sleepUntilUnpenalized(ctx context.Context, hs *hostState) error {
+	hs.mu.Lock()
+	wait := time.Until(hs.penalizedTil)
+	hs.mu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Penalize records a 429/503 response from host: requests to it pause
+// for retryAfter, and its rate limit is halved for the minute that
+// follows. Repeated penalties within that minute extend it rather than
+// letting an earlier penalty's timer restore the rate early.
+func (l *Limiter) Penalize(host string, retryAfter time.Duration) {
+	hs := l.stateFor(host)
+
+	hs.mu.Lock()
+	hs.penalizedTil = time.Now().Add(retryAfter)
+	hs.rateRestoreAt = time.Now().Add(penaltyDuration)
+	if hs.restoreTimer == nil {
+		hs.restoreTimer = time.AfterFunc(penaltyDuration, func() { l.maybeRestoreRate(hs) })
+	} else {
+		hs.restoreTimer.Reset(penaltyDuration)
+	}
+	hs.mu.Unlock()
+
+	hs.limiter.SetLimit(rate.Limit(l.rps / 2))
+}
+
+// maybeRestoreRate fires when a host's restoreTimer expires. If a later
+// Penalize call has since pushed rateRestoreAt further out, it
+// reschedules for the remaining time instead of restoring early.
+func (l *Limiter) maybeRestoreRate(hs *hostState) {
+	hs.mu.Lock()
+	remaining := time.Until(hs.rateRestoreAt)
+	if remaining > 0 {
+		hs.restoreTimer.Reset(remaining)
+		hs.mu.Unlock()
+		return
+	}
+	hs.mu.Unlock()
+
+	hs.limiter.SetLimit(rate.Limit(l.rps))
+}
+
+// Backoff returns how long to wait before retrying a failed fetch for
+// the given attempt number (0-indexed), using exponential backoff with
+// full jitter, capped at 30s.
+func Backoff(attempt int) time.Duration {
+	d := backoffBase << attempt
+	if d > backoffCap || d <= 0 {
+		d = backoffCap
+	}
+	return time.Duration(rand.Int63n(int64(d)))
+}
+
+// ParseRetryAfter parses a Retry-After header (either a number of
+// seconds or an HTTP-date), defaulting to 5s if it's absent or
+// unparseable.
+func ParseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 5 * time.Second
+	}
+	if secs, err := strconv.Atoi(header); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(header); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 5 * time.Second
+}
@@ -0,0 +1,31 @@
+package hostlimiter
+
+import "testing"
+
+func TestBackoffWithinJitterBounds(t *testing.T) {
+	for attempt := 0; attempt < 8; attempt++ {
+		upper := backoffBase << attempt
+		if upper > backoffCap || upper <= 0 {
+			upper = backoffCap
+		}
+		for i := 0; i < 50; i++ {
+			d := Backoff(attempt)
+			if d < 0 {
+				t.Fatalf("Backoff(%d) = %v, want >= 0", attempt, d)
+			}
+			if d >= upper {
+				t.Fatalf("Backoff(%d) = %v, want < %v", attempt, d, upper)
+			}
+		}
+	}
+}
+
+func TestBackoffCapsAtHighAttempts(t *testing.T) {
+	// Without the cap, backoffBase<<attempt overflows time.Duration well
+	// before attempt 40.
+	for _, attempt := range []int{10, 20, 40, 63} {
+		if d := Backoff(attempt); d > backoffCap {
+			t.Fatalf("Backoff(%d) = %v, want <= %v", attempt, d, backoffCap)
+		}
+	}
+}
@@ -1,11 +1,18 @@
 package main
 
 import (
-	"encoding/xml"
+	"bufio"
+	"context"
 	"flag"
 	"fmt"
+	"io"
 	"net/http"
+	"net/http/httputil"
+	"net/url"
 	"os"
+	"os/signal"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -13,35 +20,150 @@ import (
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/charmbracelet/log"
+	"github.com/lai0xn/sitemap-generator/crawlstate"
+	"github.com/lai0xn/sitemap-generator/hostlimiter"
+	"github.com/lai0xn/sitemap-generator/metrics"
+	"github.com/lai0xn/sitemap-generator/robots"
+	"github.com/lai0xn/sitemap-generator/warc"
 )
 
+// repeatableFlag collects every occurrence of a flag (e.g. repeated
+// -exclude patterns) into a slice instead of keeping only the last one.
+type repeatableFlag []string
+
+func (f *repeatableFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *repeatableFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
 var maxLinks int64 // Maximum number of links to crawl
 
-// URLSet represents the sitemap XML structure
-type URLSet struct {
-	XMLName xml.Name `xml:"urlset"`
-	XMLNs   string   `xml:"xmlns,attr"`
-	URLs    []URL    `xml:"url"`
+// link is a single item of crawl work: a URL, how many hops it is from
+// the base URL, and how many times it's already been retried.
+type link struct {
+	url     string
+	depth   int
+	attempt int
+}
+
+// queue is an unbounded FIFO of pending links shared by the worker
+// pool. It tracks how many links are queued or currently being
+// processed, and closes itself once that count drops to zero so
+// workers blocked in pop can return.
+type queue struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	items   []link
+	pending int
+	closed  bool
+}
+
+func newQueue() *queue {
+	q := &queue{}
+	q.cond = sync.NewCond(&q.mu)
+	return q
 }
 
-// URL represents a URL entry in the sitemap
-type URL struct {
-	Loc string `xml:"loc"`
+// push enqueues l and counts it as pending work.
+func (q *queue) push(l link) {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	q.pending++
+	q.items = append(q.items, l)
+	q.mu.Unlock()
+	q.cond.Signal()
+}
+
+// pop blocks until a link is available, the queue is closed, or all
+// pending work has drained. ok is false once there's nothing left.
+func (q *queue) pop() (l link, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.items) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if len(q.items) == 0 {
+		return link{}, false
+	}
+	l, q.items = q.items[0], q.items[1:]
+	return l, true
+}
+
+// done marks one unit of pending work as finished, closing the queue
+// once nothing is left queued or in flight.
+func (q *queue) done() {
+	q.mu.Lock()
+	q.pending--
+	drained := q.pending == 0
+	q.mu.Unlock()
+	if drained {
+		q.close()
+	}
+}
+
+// close wakes every goroutine blocked in pop so workers can exit.
+func (q *queue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.cond.Broadcast()
+}
+
+// pushAfter enqueues l once d has elapsed, counting it as pending
+// immediately so the queue doesn't consider itself drained (and close)
+// while the retry is still waiting to run.
+func (q *queue) pushAfter(d time.Duration, l link) {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	q.pending++
+	q.mu.Unlock()
+
+	time.AfterFunc(d, func() {
+		q.mu.Lock()
+		if q.closed {
+			q.mu.Unlock()
+			q.done() // the queue drained or was cancelled before this retry could run
+			return
+		}
+		q.items = append(q.items, l)
+		q.mu.Unlock()
+		q.cond.Signal()
+	})
 }
 
 type Crawler struct {
-	logger      log.Logger          // Logger for output
-	baseUrl     string              // Base URL to start crawling from
-	mu          sync.Mutex          // Mutex to synchronize access to shared data
-	urlCount    int64               // Count of URLs crawled, using atomic operations
-	seen        map[string]struct{} // Set of seen URLs to avoid duplicates
-	wg          sync.WaitGroup      // WaitGroup to wait for all goroutines to finish
-	stopOnce    sync.Once           // Ensures that the stop channel is closed only once
-	stopChan    chan struct{}       // Channel to signal stopping of crawling
-	sitemapPath string              // Path to the sitemap XML file
-}
-
-func NewCrawler(url, sitemapPath string) *Crawler {
+	logger      log.Logger           // Logger for output
+	baseUrl     string               // Base URL to start crawling from
+	mu          sync.Mutex           // Mutex to synchronize access to shared data
+	urlCount    int64                // Count of URLs crawled, using atomic operations
+	seen        map[string]urlRecord // Seen URLs and the sitemap metadata recorded for each
+	queue       *queue               // Pending/in-flight crawl work
+	concurrency int                  // Number of worker goroutines fetching concurrently
+	maxDepth    int                  // Maximum link depth from baseUrl to follow
+	sitemapPath string               // Path to the sitemap XML (optionally .gz) file
+	warcWriter  *warc.Writer         // Optional WARC writer, nil unless -warc is set
+	state       *crawlstate.Store    // Optional resumable crawl state, nil unless -resume is set
+	scope       ScopePolicy          // Decides which discovered URLs are in scope to crawl
+	robotsCache *robots.Cache        // robots.txt cache gating fetches, nil if disabled
+	userAgent   string               // User-Agent sent with every request
+	changeFreq  string               // Optional <changefreq> applied to every URL, empty to omit
+	priority    string               // Optional <priority> applied to every URL, empty to omit
+	hostLimiter *hostlimiter.Limiter // Per-host rate limit and concurrency cap
+	maxRetries  int                  // Maximum re-fetch attempts for a failed URL
+	metrics     *metrics.Metrics     // Fetch/error/retry counters, always populated
+}
+
+func NewCrawler(url, sitemapPath string, concurrency, maxDepth int, scope ScopePolicy, userAgent, changeFreq, priority string, hostLimiter *hostlimiter.Limiter, maxRetries int) *Crawler {
 	logger := log.NewWithOptions(os.Stderr, log.Options{
 		ReportCaller:    true,
 		ReportTimestamp: true,
@@ -49,108 +171,321 @@ func NewCrawler(url, sitemapPath string) *Crawler {
 		Prefix:          "Crawler 🕸️",
 	})
 
+	m := metrics.New()
+
 	return &Crawler{
 		logger:      *logger,
 		baseUrl:     url,
-		seen:        make(map[string]struct{}),
-		stopChan:    make(chan struct{}),
+		seen:        make(map[string]urlRecord),
+		queue:       newQueue(),
+		concurrency: concurrency,
+		maxDepth:    maxDepth,
 		sitemapPath: sitemapPath,
+		scope:       scope,
+		robotsCache: robots.NewCache(userAgent, hostLimiter, m),
+		userAgent:   userAgent,
+		changeFreq:  changeFreq,
+		priority:    priority,
+		hostLimiter: hostLimiter,
+		maxRetries:  maxRetries,
+		metrics:     m,
 	}
 }
 
-func (c *Crawler) ExtractLinks(url string) {
-	defer c.wg.Done() // Decrement the WaitGroup counter when this function completes
+// Run starts the worker pool, seeds it with the base URL, and blocks
+// until the crawl is complete or ctx is cancelled. On cancellation,
+// workers stop picking up new links so the sitemap can be flushed with
+// whatever was crawled so far.
+func (c *Crawler) Run(ctx context.Context) {
+	var workers sync.WaitGroup
+	for i := 0; i < c.concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			c.worker(ctx)
+		}()
+	}
+
+	go func() {
+		<-ctx.Done()
+		c.queue.close() // unblock any worker waiting in pop so it can observe cancellation
+	}()
+
+	c.queue.push(link{url: c.baseUrl, depth: 0})
+	workers.Wait()
+}
+
+// worker repeatedly pops a link off the queue and processes it until
+// the queue drains or ctx is cancelled.
+func (c *Crawler) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		l, ok := c.queue.pop()
+		if !ok {
+			return
+		}
+		c.process(ctx, l)
+	}
+}
+
+// process fetches l.url, extracts its outbound links, and enqueues
+// the ones within maxDepth for the worker pool to pick up next.
+func (c *Crawler) process(ctx context.Context, l link) {
+	defer c.queue.done() // Count this unit of work as finished when we return, however we exit
+
+	url := l.url
 
 	// Check if the maximum number of links has been reached
 	if atomic.LoadInt64(&c.urlCount) >= maxLinks {
-		c.stopOnce.Do(func() {
-			close(c.stopChan) // Close stopChan only once to signal termination
-		})
+		return
+	}
+
+	// Skip URLs a previous, interrupted run already finished
+	if c.state != nil && c.state.IsDone(url) {
+		c.logger.Info("Skipping already-crawled link: ", url)
+		return
+	}
+
+	// Skip URLs robots.txt disallows for our user agent
+	if c.robotsCache != nil && !c.robotsCache.Allowed(ctx, url) {
+		c.logger.Info("Skipping robots.txt-disallowed link: ", url)
 		return
 	}
 
 	// Create a new HTTP request
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		c.logger.Warn(err) // Log the error
 		return
 	}
+	req.Header.Set("User-Agent", c.userAgent)
+
+	// Wait for this host's rate limit and concurrency slot before
+	// fetching, so one busy site can't starve the rest of the crawl.
+	release, err := c.hostLimiter.Acquire(ctx, req.URL.Host)
+	if err != nil {
+		return // ctx was cancelled while waiting
+	}
+	defer release()
+
+	c.metrics.IncFetches()
 
 	// Perform the HTTP request
 	res, err := http.DefaultClient.Do(req)
 	if err != nil {
 		c.logger.Warn(err) // Log the error
+		c.metrics.IncFetchError("network")
+		if c.state != nil {
+			c.state.Mark(url, crawlstate.Failed)
+		}
+		c.retry(l)
 		return
 	}
 	defer res.Body.Close() // Ensure the response body is closed after use
 
+	if retryable, retryAfter := c.classifyStatus(req.URL.Host, res); retryable {
+		if c.state != nil {
+			c.state.Mark(url, crawlstate.Failed)
+		}
+		c.retryAfter(l, retryAfter)
+		return
+	}
+
 	// Check if the response status code is 200 OK
 	if res.StatusCode != http.StatusOK {
 		c.logger.Warn("Non-200 response status code:", res.StatusCode)
+		c.metrics.IncFetchError(strconv.Itoa(res.StatusCode))
+		if c.state != nil {
+			c.state.Mark(url, crawlstate.Failed)
+		}
+		if res.StatusCode >= 500 {
+			c.retry(l) // sustained 5xx: back off and re-queue rather than dropping the URL
+		}
 		return
 	}
 
+	rec := c.recordURL(url, res.Header.Get("Last-Modified"))
+
+	// When writing WARC, copy the body to a temp file and parse it from
+	// there, since the WARC response record and goquery each read it in
+	// full. Both still hold the whole body in memory while they read it.
+	body := res.Body
+	if c.warcWriter != nil {
+		tmp, err := c.bufferResponse(req, res)
+		if err != nil {
+			c.logger.Warn(err)
+			return
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+		body = tmp
+	}
+
+	counted := &countingReader{r: body}
+
 	// Parse the HTML document
-	doc, err := goquery.NewDocumentFromReader(res.Body)
+	doc, err := goquery.NewDocumentFromReader(counted)
+	c.metrics.AddBytes(counted.n)
 	if err != nil {
 		c.logger.Warn(err) // Log the error
 		return
 	}
 
-	// Find and process all anchor tags
-	doc.Find("a").Each(func(i int, item *goquery.Selection) {
-		href, exists := item.Attr("href") // Extract the href attribute
-		if exists && strings.HasPrefix(href, c.baseUrl) {
-			c.mu.Lock() // Lock the mutex to safely update shared data
-			if _, seen := c.seen[href]; !seen {
-				c.seen[href] = struct{}{} // Mark the URL as seen
-				if atomic.AddInt64(&c.urlCount, 1) <= maxLinks {
-					c.logger.Info("Link Found: ", href)
-					c.wg.Add(1)             // Increment the WaitGroup counter
-					go c.ExtractLinks(href) // Crawl the found link in a new goroutine
-				}
+	if c.state != nil {
+		c.state.MarkDone(url, crawlstate.Record{
+			LastModified: rec.LastModified,
+			ChangeFreq:   rec.ChangeFreq,
+			Priority:     rec.Priority,
+		})
+	}
+
+	// A page at maxDepth is still recorded in the sitemap above via
+	// seen, but its links are not extracted or followed.
+	if l.depth >= c.maxDepth {
+		return
+	}
+
+	// Resolve every discovered resource (links, images, scripts, iframes,
+	// CSS background images) against the page URL and queue the ones
+	// that fall within scope.
+	for _, raw := range extractResourceURLs(doc) {
+		href := c.resolve(url, raw)
+		if href == "" || !c.scope.InScope(href) {
+			continue
+		}
+
+		c.mu.Lock() // Lock the mutex to safely update shared data
+		if _, seen := c.seen[href]; !seen {
+			c.seen[href] = urlRecord{Loc: href} // Mark the URL as seen, metadata filled in once it's fetched
+			if atomic.AddInt64(&c.urlCount, 1) <= maxLinks {
+				c.logger.Info("Link Found: ", href)
+				c.queue.push(link{url: href, depth: l.depth + 1}) // Queue the found link for a worker to fetch
 			}
-			c.mu.Unlock()
 		}
-	})
+		c.mu.Unlock()
+	}
+}
+
+// countingReader wraps an io.Reader to tally how many bytes were read
+// through it, for the bytes_fetched_total metric.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// classifyStatus reports whether res is a 429/503 that should be
+// retried after honoring the host's rate-limit penalty, and how long
+// to wait before retrying.
+func (c *Crawler) classifyStatus(host string, res *http.Response) (retryable bool, wait time.Duration) {
+	if res.StatusCode != http.StatusTooManyRequests && res.StatusCode != http.StatusServiceUnavailable {
+		return false, 0
+	}
+	wait = hostlimiter.ParseRetryAfter(res.Header.Get("Retry-After"))
+	c.metrics.IncFetchError(strconv.Itoa(res.StatusCode))
+	c.hostLimiter.Penalize(host, wait)
+	return true, wait
+}
+
+// retry re-queues l with exponential backoff after a transport-level
+// failure or a 5xx response, up to maxRetries attempts.
+func (c *Crawler) retry(l link) {
+	if l.attempt >= c.maxRetries {
+		c.logger.Warn("Giving up after max retries: ", l.url)
+		return
+	}
+	c.metrics.IncRetries()
+	c.queue.pushAfter(hostlimiter.Backoff(l.attempt), link{url: l.url, depth: l.depth, attempt: l.attempt + 1})
+}
+
+// retryAfter re-queues l after the given delay (e.g. a server's
+// Retry-After), up to maxRetries attempts.
+func (c *Crawler) retryAfter(l link, wait time.Duration) {
+	if l.attempt >= c.maxRetries {
+		c.logger.Warn("Giving up after max retries: ", l.url)
+		return
+	}
+	c.metrics.IncRetries()
+	c.queue.pushAfter(wait, link{url: l.url, depth: l.depth, attempt: l.attempt + 1})
 }
 
-// writeSitemap writes all URLs to the sitemap XML file
-func (c *Crawler) WriteSitemap() error {
+// recordURL stores (or updates) the sitemap entry for url once it has
+// been successfully fetched, parsing lastModified if the server sent
+// one and applying the crawler's configured changefreq/priority. It
+// returns the recorded entry so callers can persist it to crawl state.
+func (c *Crawler) recordURL(url, lastModified string) urlRecord {
+	rec := urlRecord{Loc: url, ChangeFreq: c.changeFreq, Priority: c.priority}
+	if t, err := http.ParseTime(lastModified); err == nil {
+		rec.LastModified = t
+	}
+
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	c.seen[url] = rec
+	c.mu.Unlock()
+	return rec
+}
 
-	// Open the sitemap file for writing
-	file, err := os.Create(c.sitemapPath)
+// resolve turns a possibly-relative resource reference found on
+// pageURL into an absolute URL, or "" if it can't be resolved.
+func (c *Crawler) resolve(pageURL, ref string) string {
+	base, err := url.Parse(pageURL)
 	if err != nil {
-		return err
+		return ""
 	}
-	defer file.Close()
-
-	// Write the XML header
-	_, err = file.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	target, err := base.Parse(ref)
 	if err != nil {
-		return err
+		return ""
 	}
+	return target.String()
+}
 
-	// Create URLSet for the sitemap
-	urlSet := URLSet{
-		XMLNs: "http://www.sitemaps.org/schemas/sitemap/0.9",
+// bufferResponse writes WARC request/response records for req/res and
+// returns the response body copied into a temp file, rewound to its
+// start, so callers can parse it with goquery after the WARC writer has
+// read it.
+func (c *Crawler) bufferResponse(req *http.Request, res *http.Response) (*os.File, error) {
+	reqDump, err := httputil.DumpRequest(req, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.warcWriter.WriteRequest(req.URL.String(), reqDump); err != nil {
+		c.logger.Warn(err)
 	}
 
-	// Populate the URLSet with URLs from the seen map
-	for url := range c.seen {
-		urlSet.URLs = append(urlSet.URLs, URL{Loc: url})
+	respHeaders, err := httputil.DumpResponse(res, false)
+	if err != nil {
+		return nil, err
 	}
 
-	// Encode the URLSet to XML
-	encoder := xml.NewEncoder(file)
-	encoder.Indent("", "  ")
-	if err := encoder.Encode(urlSet); err != nil {
-		return err
+	tmp, err := os.CreateTemp("", "sitemap-crawl-body-*")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(tmp, res.Body); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	if _, err := tmp.Seek(0, 0); err != nil {
+		return nil, err
 	}
 
-	return nil
+	if err := c.warcWriter.WriteResponse(req.URL.String(), respHeaders, tmp); err != nil {
+		c.logger.Warn(err)
+	}
+	if _, err := tmp.Seek(0, 0); err != nil {
+		return nil, err
+	}
+	return tmp, nil
 }
 
 // Close cleans up resources
@@ -158,6 +493,40 @@ func (c *Crawler) Close() {
 	// Optionally, you can perform cleanup tasks here
 }
 
+// compileExcludes compiles every -exclude pattern plus any patterns
+// listed one-per-line in excludeFromFile (blank lines ignored).
+func compileExcludes(patterns []string, excludeFromFile string) ([]*regexp.Regexp, error) {
+	all := append([]string{}, patterns...)
+
+	if excludeFromFile != "" {
+		f, err := os.Open(excludeFromFile)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				all = append(all, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	excludes := make([]*regexp.Regexp, 0, len(all))
+	for _, pattern := range all {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		excludes = append(excludes, re)
+	}
+	return excludes, nil
+}
+
 func main() {
 	// Logger for argument parsing
 	ArgsLogger := log.NewWithOptions(os.Stderr, log.Options{
@@ -171,6 +540,24 @@ func main() {
 	outputPath := flag.String("o", "./sitemap.xml", "set the output file")
 	flag.Int64Var(&maxLinks, "n", 100, "number of links to crawl")
 	url := flag.String("t", "", "target url")
+	warcPath := flag.String("warc", "", "also write a WARC file here, alongside the sitemap")
+	outputMaxSize := flag.Int64("output-max-size", 1<<30, "start a new WARC segment before a record would take the current one past this many uncompressed bytes")
+	resumeDir := flag.String("resume", "", "directory holding crawl state to resume an interrupted crawl")
+	concurrency := flag.Int("c", 10, "number of concurrent fetch workers")
+	maxDepth := flag.Int("depth", 10, "maximum link depth to follow from the target url")
+	schemes := flag.String("schemes", "http,https", "comma-separated list of URL schemes to crawl")
+	includeSubdomains := flag.Bool("include-subdomains", false, "also crawl subdomains of the target url's host")
+	userAgent := flag.String("user-agent", "sitemap-generator/1.0", "User-Agent sent with every request, including robots.txt fetches")
+	changeFreq := flag.String("changefreq", "", "optional <changefreq> value applied to every sitemap entry")
+	priority := flag.String("priority", "", "optional <priority> value applied to every sitemap entry")
+	var excludePatterns repeatableFlag
+	flag.Var(&excludePatterns, "exclude", "regex of URLs to exclude from crawling (repeatable)")
+	excludeFromFile := flag.String("exclude-from-file", "", "file of newline-separated regexes to exclude from crawling")
+	rps := flag.Float64("rps", 2, "maximum requests per second to any single host")
+	burst := flag.Int("burst", 5, "burst size for the per-host rate limiter")
+	perHostConcurrency := flag.Int("per-host-concurrency", 2, "maximum concurrent in-flight requests to any single host")
+	maxRetries := flag.Int("max-retries", 3, "maximum retries for a failed fetch before giving up on it")
+	metricsAddr := flag.String("metrics-addr", "", "if set, serve Prometheus-style crawl metrics at http://<addr>/metrics")
 
 	ArgsLogger.Info("Parsing Args")
 
@@ -180,13 +567,83 @@ func main() {
 		return
 	}
 
-	crwl := NewCrawler(*url, *outputPath)
+	excludes, err := compileExcludes(excludePatterns, *excludeFromFile)
+	if err != nil {
+		ArgsLogger.Error("Failed to load -exclude patterns:", err)
+		return
+	}
+
+	scope, err := NewScopePolicy(*url, strings.Split(*schemes, ","), excludes, *includeSubdomains)
+	if err != nil {
+		ArgsLogger.Error("Invalid target url:", err)
+		return
+	}
+
+	limiter := hostlimiter.New(*rps, *burst, *perHostConcurrency)
+	crwl := NewCrawler(*url, *outputPath, *concurrency, *maxDepth, scope, *userAgent, *changeFreq, *priority, limiter, *maxRetries)
+
+	if *metricsAddr != "" {
+		go func() {
+			if err := crwl.metrics.ServeAddr(*metricsAddr); err != nil {
+				ArgsLogger.Error("Metrics server stopped:", err)
+			}
+		}()
+	}
+
+	if *warcPath != "" {
+		w, err := warc.NewWriter(*warcPath, *outputMaxSize)
+		if err != nil {
+			ArgsLogger.Error("Failed to open WARC output:", err)
+			return
+		}
+		defer w.Close()
+		if err := w.WriteInfo("sitemap-generator", *url); err != nil {
+			ArgsLogger.Error("Failed to write warcinfo record:", err)
+			return
+		}
+		crwl.warcWriter = w
+	}
+
+	if *resumeDir != "" {
+		if err := os.MkdirAll(*resumeDir, 0o755); err != nil {
+			ArgsLogger.Error("Failed to create resume directory:", err)
+			return
+		}
+		state, err := crawlstate.Open(*resumeDir)
+		if err != nil {
+			ArgsLogger.Error("Failed to open crawl state:", err)
+			return
+		}
+		defer state.Close()
+		crwl.state = state
+
+		// Restore the sitemap entries for URLs a previous, interrupted
+		// run already finished, since process() skips re-fetching them
+		// and would otherwise never add them to crwl.seen.
+		done, err := state.DoneRecords()
+		if err != nil {
+			ArgsLogger.Error("Failed to read crawl state:", err)
+			return
+		}
+		for url, rec := range done {
+			crwl.seen[url] = urlRecord{
+				Loc:          rec.Loc,
+				LastModified: rec.LastModified,
+				ChangeFreq:   rec.ChangeFreq,
+				Priority:     rec.Priority,
+			}
+		}
+		if len(done) > 0 {
+			ArgsLogger.Info("Resuming crawl with previously-completed URLs: ", len(done))
+		}
+	}
 
-	crwl.wg.Add(1)             // Add one to the WaitGroup for the initial goroutine
-	go crwl.ExtractLinks(*url) // Start the crawling process
+	// SIGINT cancels the crawl so Run returns early and whatever was
+	// crawled so far still gets flushed to the sitemap below.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 
-	// Wait for all goroutines to finish
-	crwl.wg.Wait()
+	crwl.Run(ctx) // Blocks until the crawl finishes or ctx is cancelled
 
 	// Write the sitemap XML file
 	if err := crwl.WriteSitemap(); err != nil {
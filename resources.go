@@ -0,0 +1,63 @@
+package main
+
+import (
+	"regexp"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// cssURLPattern matches the url(...) function used by CSS
+// background/background-image declarations, in both inline style
+// attributes and <style> blocks.
+var cssURLPattern = regexp.MustCompile(`url\(\s*['"]?([^'")]+)['"]?\s*\)`)
+
+// resourceSelectors maps each tag/attribute pair the crawler follows
+// beyond plain <a href> links, so the full resource graph (stylesheets,
+// scripts, images, frames) ends up in the sitemap/WARC output.
+var resourceSelectors = []struct {
+	selector  string
+	attribute string
+}{
+	{"a", "href"},
+	{"link", "href"},
+	{"img", "src"},
+	{"script", "src"},
+	{"iframe", "src"},
+}
+
+// extractResourceURLs returns every raw (possibly relative) URL
+// referenced by doc: anchors, stylesheet/script/image/frame
+// references, and CSS background-image URLs from inline styles and
+// <style> blocks.
+func extractResourceURLs(doc *goquery.Document) []string {
+	var urls []string
+
+	for _, sel := range resourceSelectors {
+		doc.Find(sel.selector).Each(func(i int, item *goquery.Selection) {
+			if v, exists := item.Attr(sel.attribute); exists {
+				urls = append(urls, v)
+			}
+		})
+	}
+
+	doc.Find("[style]").Each(func(i int, item *goquery.Selection) {
+		if style, exists := item.Attr("style"); exists {
+			urls = append(urls, cssURLMatches(style)...)
+		}
+	})
+
+	doc.Find("style").Each(func(i int, item *goquery.Selection) {
+		urls = append(urls, cssURLMatches(item.Text())...)
+	})
+
+	return urls
+}
+
+func cssURLMatches(css string) []string {
+	matches := cssURLPattern.FindAllStringSubmatch(css, -1)
+	urls := make([]string, 0, len(matches))
+	for _, m := range matches {
+		urls = append(urls, m[1])
+	}
+	return urls
+}
@@ -0,0 +1,263 @@
+// Package robots fetches and caches each host's /robots.txt and
+// answers whether a given URL may be fetched by a named user agent.
+package robots
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lai0xn/sitemap-generator/hostlimiter"
+	"github.com/lai0xn/sitemap-generator/metrics"
+)
+
+// rule is a single Allow/Disallow path prefix from one robots.txt
+// group.
+type rule struct {
+	path     string
+	allow    bool
+	specific int // len(path), used to pick the most specific matching rule
+}
+
+// group is one User-agent block of a robots.txt file.
+type group struct {
+	agents []string
+	rules  []rule
+}
+
+// ruleset is a parsed robots.txt.
+type ruleset struct {
+	groups []group
+}
+
+// Cache fetches robots.txt on first use for each scheme+host and
+// reuses the parsed result for subsequent lookups. Fetches are routed
+// through the same hostlimiter.Limiter and metrics.Metrics as page
+// fetches, so a host's own robots.txt doesn't bypass the crawl's
+// politeness controls or its counters.
+type Cache struct {
+	client      *http.Client
+	userAgent   string
+	hostLimiter *hostlimiter.Limiter
+	metrics     *metrics.Metrics
+
+	mu       sync.Mutex
+	entries  map[string]*ruleset
+	inflight map[string]chan struct{} // closed when the fetch for that key completes
+}
+
+// NewCache returns a Cache that identifies itself as userAgent when
+// fetching robots.txt and when matching its rules. robots.txt fetches
+// acquire hostLimiter's per-host rate/concurrency gate like any other
+// fetch, and are counted in m.
+func NewCache(userAgent string, hostLimiter *hostlimiter.Limiter, m *metrics.Metrics) *Cache {
+	return &Cache{
+		client:      http.DefaultClient,
+		userAgent:   userAgent,
+		hostLimiter: hostLimiter,
+		metrics:     m,
+		entries:     make(map[string]*ruleset),
+		inflight:    make(map[string]chan struct{}),
+	}
+}
+
+// Allowed reports whether rawURL may be fetched under the target
+// host's robots.txt. A robots.txt that can't be fetched (missing,
+// network error, non-200) is treated as allow-all, per the standard.
+func (c *Cache) Allowed(ctx context.Context, rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return true
+	}
+
+	rs := c.rulesetFor(ctx, u)
+	if rs == nil {
+		return true
+	}
+	return rs.allowed(c.userAgent, u.Path)
+}
+
+// rulesetFor returns the cached ruleset for u's scheme+host, fetching
+// it on first use. Concurrent callers for a host with no entry yet
+// wait on the same in-flight fetch rather than each firing their own.
+func (c *Cache) rulesetFor(ctx context.Context, u *url.URL) *ruleset {
+	key := u.Scheme + "://" + u.Host
+
+	for {
+		c.mu.Lock()
+		if rs, ok := c.entries[key]; ok {
+			c.mu.Unlock()
+			return rs
+		}
+		if done, ok := c.inflight[key]; ok {
+			c.mu.Unlock()
+			<-done
+			continue
+		}
+		done := make(chan struct{})
+		c.inflight[key] = done
+		c.mu.Unlock()
+
+		rs := c.fetch(ctx, u.Host, key)
+
+		c.mu.Lock()
+		c.entries[key] = rs
+		delete(c.inflight, key)
+		c.mu.Unlock()
+		close(done)
+		return rs
+	}
+}
+
+// maxFetchAttempts bounds how many fetches (the first one plus retries)
+// are made for a robots.txt answering 429/503 before it's treated as
+// unfetchable (allow-all).
+const maxFetchAttempts = 3
+
+func (c *Cache) fetch(ctx context.Context, host, baseURL string) *ruleset {
+	for attempt := 0; ; attempt++ {
+		rs, retryable := c.fetchOnce(ctx, host, baseURL)
+		if !retryable || attempt >= maxFetchAttempts-1 {
+			return rs
+		}
+		select {
+		case <-time.After(hostlimiter.Backoff(attempt)):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// fetchOnce makes a single robots.txt fetch attempt, gated by the
+// shared host rate limiter and counted in the shared metrics. retryable
+// reports whether the failure was a 429/503 worth backing off and
+// retrying.
+func (c *Cache) fetchOnce(ctx context.Context, host, baseURL string) (rs *ruleset, retryable bool) {
+	release, err := c.hostLimiter.Acquire(ctx, host)
+	if err != nil {
+		return nil, false
+	}
+	defer release()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/robots.txt", nil)
+	if err != nil {
+		return nil, false
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+
+	c.metrics.IncFetches()
+	res, err := c.client.Do(req)
+	if err != nil {
+		c.metrics.IncFetchError("network")
+		return nil, false
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusServiceUnavailable {
+		wait := hostlimiter.ParseRetryAfter(res.Header.Get("Retry-After"))
+		c.metrics.IncFetchError(strconv.Itoa(res.StatusCode))
+		c.hostLimiter.Penalize(host, wait)
+		return nil, true
+	}
+
+	if res.StatusCode != http.StatusOK {
+		c.metrics.IncFetchError(strconv.Itoa(res.StatusCode))
+		return nil, false
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		c.metrics.IncFetchError("network")
+		return nil, false
+	}
+	c.metrics.AddBytes(int64(len(body)))
+
+	return parse(bytes.NewReader(body)), false
+}
+
+// parse reads a robots.txt document into a ruleset. It understands
+// User-agent, Allow, and Disallow directives; other directives (e.g.
+// Crawl-delay, Sitemap) are ignored.
+func parse(body io.Reader) *ruleset {
+	rs := &ruleset{}
+	var cur *group
+
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			cur = nil
+			continue
+		}
+
+		field, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		field = strings.ToLower(strings.TrimSpace(field))
+		value = strings.TrimSpace(value)
+		if idx := strings.Index(value, "#"); idx >= 0 {
+			value = strings.TrimSpace(value[:idx])
+		}
+
+		switch field {
+		case "user-agent":
+			if cur == nil || len(cur.rules) > 0 {
+				rs.groups = append(rs.groups, group{})
+				cur = &rs.groups[len(rs.groups)-1]
+			}
+			cur.agents = append(cur.agents, value)
+		case "allow", "disallow":
+			if cur == nil {
+				continue
+			}
+			cur.rules = append(cur.rules, rule{path: value, allow: field == "allow", specific: len(value)})
+		}
+	}
+	return rs
+}
+
+// allowed implements the standard longest-match-wins algorithm over
+// the first User-agent group naming userAgent, falling back to the "*"
+// group when no named group matches.
+func (rs *ruleset) allowed(userAgent, path string) bool {
+	g := rs.matchGroup(userAgent)
+	if g == nil {
+		return true
+	}
+
+	best := -1
+	allow := true
+	for _, r := range g.rules {
+		if r.path == "" || !strings.HasPrefix(path, r.path) {
+			continue
+		}
+		if r.specific > best {
+			best = r.specific
+			allow = r.allow
+		}
+	}
+	return allow
+}
+
+func (rs *ruleset) matchGroup(userAgent string) *group {
+	var wildcard *group
+	for i := range rs.groups {
+		g := &rs.groups[i]
+		for _, agent := range g.agents {
+			if agent == "*" {
+				wildcard = g
+			} else if strings.EqualFold(agent, userAgent) || strings.HasPrefix(strings.ToLower(userAgent), strings.ToLower(agent)) {
+				return g
+			}
+		}
+	}
+	return wildcard
+}
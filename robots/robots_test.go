@@ -0,0 +1,50 @@
+package robots
+
+import "testing"
+
+func TestRulesetAllowedLongestMatchWins(t *testing.T) {
+	rs := &ruleset{groups: []group{{
+		agents: []string{"*"},
+		rules: []rule{
+			{path: "/private", allow: false, specific: len("/private")},
+			{path: "/private/public", allow: true, specific: len("/private/public")},
+		},
+	}}}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/private/secret", false},     // only the shorter Disallow matches
+		{"/private/public/page", true}, // longer Allow wins over the Disallow prefix
+		{"/open", true},                // no rule matches, default allow
+	}
+	for _, tt := range tests {
+		if got := rs.allowed("anybot", tt.path); got != tt.want {
+			t.Errorf("allowed(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRulesetAllowedGroupPrecedence(t *testing.T) {
+	rs := &ruleset{groups: []group{
+		{agents: []string{"*"}, rules: []rule{{path: "/", allow: false, specific: 1}}},
+		{agents: []string{"mybot"}, rules: []rule{{path: "/", allow: true, specific: 1}}},
+	}}
+
+	if !rs.allowed("mybot", "/anything") {
+		t.Error("named group should take precedence over wildcard, want allow")
+	}
+	if rs.allowed("otherbot", "/anything") {
+		t.Error("unmatched agent should fall back to wildcard, want disallow")
+	}
+}
+
+func TestRulesetAllowedNoMatchingGroup(t *testing.T) {
+	rs := &ruleset{groups: []group{
+		{agents: []string{"mybot"}, rules: []rule{{path: "/", allow: false, specific: 1}}},
+	}}
+	if !rs.allowed("otherbot", "/anything") {
+		t.Error("no matching group and no wildcard should default to allow")
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ScopePolicy decides whether a discovered URL should be crawled.
+// The crawler calls it instead of hard-coding a single prefix check;
+// the -schemes/-exclude/-include-subdomains flags configure the
+// default implementation, schemeHostScope.
+type ScopePolicy interface {
+	InScope(rawURL string) bool
+}
+
+// schemeHostScope is the default ScopePolicy: it restricts crawling to
+// an allow-list of URL schemes and to the base URL's host (or its
+// subdomains, if includeSubdomains is set), then rejects anything
+// matching one of excludes.
+type schemeHostScope struct {
+	base              *url.URL
+	schemes           map[string]struct{}
+	excludes          []*regexp.Regexp
+	includeSubdomains bool
+}
+
+// NewScopePolicy builds the default ScopePolicy rooted at baseURL.
+func NewScopePolicy(baseURL string, schemes []string, excludes []*regexp.Regexp, includeSubdomains bool) (ScopePolicy, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	schemeSet := make(map[string]struct{}, len(schemes))
+	for _, s := range schemes {
+		schemeSet[strings.ToLower(strings.TrimSpace(s))] = struct{}{}
+	}
+
+	return &schemeHostScope{
+		base:              base,
+		schemes:           schemeSet,
+		excludes:          excludes,
+		includeSubdomains: includeSubdomains,
+	}, nil
+}
+
+func (s *schemeHostScope) InScope(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+
+	if _, ok := s.schemes[strings.ToLower(u.Scheme)]; !ok {
+		return false
+	}
+
+	if !s.sameHost(u.Hostname()) {
+		return false
+	}
+
+	for _, re := range s.excludes {
+		if re.MatchString(rawURL) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *schemeHostScope) sameHost(host string) bool {
+	baseHost := s.base.Hostname()
+	if host == baseHost {
+		return true
+	}
+	if s.includeSubdomains {
+		return strings.HasSuffix(host, "."+baseHost)
+	}
+	return false
+}
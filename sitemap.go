@@ -0,0 +1,230 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// sitemaps.org caps each sitemap file at 50,000 URLs and 50 MiB
+// uncompressed; WriteSitemap splits across multiple files once either
+// limit is hit.
+const (
+	maxURLsPerShard  = 50000
+	maxBytesPerShard = 50 * 1024 * 1024
+)
+
+// urlRecord is the sitemap metadata tracked for one crawled URL.
+type urlRecord struct {
+	Loc          string
+	LastModified time.Time
+	ChangeFreq   string
+	Priority     string
+}
+
+// URLSet is the root element of a single sitemap shard.
+type URLSet struct {
+	XMLName xml.Name `xml:"urlset"`
+	XMLNs   string   `xml:"xmlns,attr"`
+	URLs    []URL    `xml:"url"`
+}
+
+// URL represents one <url> entry in a sitemap shard.
+type URL struct {
+	Loc        string `xml:"loc"`
+	LastMod    string `xml:"lastmod,omitempty"`
+	ChangeFreq string `xml:"changefreq,omitempty"`
+	Priority   string `xml:"priority,omitempty"`
+}
+
+// sitemapIndex is the root element of sitemap_index.xml, referencing
+// every shard WriteSitemap produced.
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	XMLNs    string       `xml:"xmlns,attr"`
+	Sitemaps []sitemapRef `xml:"sitemap"`
+}
+
+// sitemapRef is one <sitemap> entry in sitemap_index.xml.
+type sitemapRef struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// shard records where one sitemap file ended up and the newest
+// LastModified among the URLs it contains, for the index's <lastmod>.
+type shard struct {
+	path    string
+	lastMod time.Time
+}
+
+// WriteSitemap writes every crawled URL to one or more sitemap shards
+// at c.sitemapPath (named "<stem>-00001<ext>", "<stem>-00002<ext>", …,
+// gzip-compressed when the path ends in .gz), plus a sitemap_index.xml
+// alongside them referencing each shard.
+func (c *Crawler) WriteSitemap() error {
+	c.mu.Lock()
+	records := make([]urlRecord, 0, len(c.seen))
+	for _, rec := range c.seen {
+		records = append(records, rec)
+	}
+	c.mu.Unlock()
+
+	sort.Slice(records, func(i, j int) bool { return records[i].Loc < records[j].Loc })
+
+	shards, err := writeShards(c.sitemapPath, records)
+	if err != nil {
+		return err
+	}
+	return writeSitemapIndex(c.sitemapPath, c.baseUrl, shards)
+}
+
+// writeShards splits records across size- and count-capped sitemap
+// files and returns where each one landed.
+func writeShards(basePath string, records []urlRecord) ([]shard, error) {
+	var shards []shard
+	var current []urlRecord
+	var currentBytes int64
+
+	flush := func() error {
+		path := shardPath(basePath, len(shards)+1)
+		if err := writeShard(path, current); err != nil {
+			return err
+		}
+		shards = append(shards, shard{path: path, lastMod: newestModTime(current)})
+		current = nil
+		currentBytes = 0
+		return nil
+	}
+
+	for _, rec := range records {
+		size := entrySize(rec)
+		if len(current) > 0 && (len(current) >= maxURLsPerShard || currentBytes+size > maxBytesPerShard) {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+		current = append(current, rec)
+		currentBytes += size
+	}
+
+	// Always emit at least one shard, even for an empty crawl, so the
+	// sitemap index has something valid to point at.
+	if len(current) > 0 || len(shards) == 0 {
+		if err := flush(); err != nil {
+			return nil, err
+		}
+	}
+	return shards, nil
+}
+
+// entrySize estimates the marshaled size of rec's <url> element, used
+// to decide when a shard has hit the 50 MiB sitemaps.org limit.
+func entrySize(rec urlRecord) int64 {
+	b, err := xml.Marshal(toURL(rec))
+	if err != nil {
+		return int64(len(rec.Loc))
+	}
+	return int64(len(b))
+}
+
+func toURL(rec urlRecord) URL {
+	u := URL{Loc: rec.Loc, ChangeFreq: rec.ChangeFreq, Priority: rec.Priority}
+	if !rec.LastModified.IsZero() {
+		u.LastMod = rec.LastModified.Format(time.RFC3339)
+	}
+	return u
+}
+
+func newestModTime(records []urlRecord) time.Time {
+	var newest time.Time
+	for _, rec := range records {
+		if rec.LastModified.After(newest) {
+			newest = rec.LastModified
+		}
+	}
+	return newest
+}
+
+// writeShard encodes records as a single sitemap XML file at path,
+// gzip-compressing the output if path ends in .gz.
+func writeShard(path string, records []urlRecord) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var w io.Writer = file
+	if strings.HasSuffix(path, ".gz") {
+		gz := gzip.NewWriter(file)
+		defer gz.Close()
+		w = gz
+	}
+
+	if _, err := io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"); err != nil {
+		return err
+	}
+
+	urlSet := URLSet{XMLNs: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	for _, rec := range records {
+		urlSet.URLs = append(urlSet.URLs, toURL(rec))
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	return encoder.Encode(urlSet)
+}
+
+// writeSitemapIndex writes sitemap_index.xml next to basePath,
+// referencing each shard by an absolute URL rooted at baseUrl.
+func writeSitemapIndex(basePath, baseUrl string, shards []shard) error {
+	dir := filepath.Dir(basePath)
+	indexPath := filepath.Join(dir, "sitemap_index.xml")
+
+	file, err := os.Create(indexPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := io.WriteString(file, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"); err != nil {
+		return err
+	}
+
+	index := sitemapIndex{XMLNs: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	for _, s := range shards {
+		ref := sitemapRef{Loc: strings.TrimRight(baseUrl, "/") + "/" + filepath.Base(s.path)}
+		if !s.lastMod.IsZero() {
+			ref.LastMod = s.lastMod.Format(time.RFC3339)
+		}
+		index.Sitemaps = append(index.Sitemaps, ref)
+	}
+
+	encoder := xml.NewEncoder(file)
+	encoder.Indent("", "  ")
+	return encoder.Encode(index)
+}
+
+// shardPath computes the "<stem>-NNNNN<ext>" path for shard n of
+// basePath, preserving a trailing .gz suffix if present.
+func shardPath(basePath string, n int) string {
+	dir, base := filepath.Split(basePath)
+	gzipped := strings.HasSuffix(base, ".gz")
+	if gzipped {
+		base = strings.TrimSuffix(base, ".gz")
+	}
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	name := fmt.Sprintf("%s-%05d%s", stem, n, ext)
+	if gzipped {
+		name += ".gz"
+	}
+	return filepath.Join(dir, name)
+}
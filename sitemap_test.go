@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readShardURLs(t *testing.T, path string) []URL {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading shard %s: %v", path, err)
+	}
+	var set URLSet
+	if err := xml.Unmarshal(data, &set); err != nil {
+		t.Fatalf("unmarshaling shard %s: %v", path, err)
+	}
+	return set.URLs
+}
+
+func TestWriteShardsSplitsOnURLCount(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "sitemap.xml")
+
+	records := make([]urlRecord, maxURLsPerShard+1)
+	for i := range records {
+		records[i] = urlRecord{Loc: fmt.Sprintf("https://example.com/%d", i)}
+	}
+
+	shards, err := writeShards(base, records)
+	if err != nil {
+		t.Fatalf("writeShards: %v", err)
+	}
+	if len(shards) != 2 {
+		t.Fatalf("got %d shards, want 2", len(shards))
+	}
+	if got := len(readShardURLs(t, shards[0].path)); got != maxURLsPerShard {
+		t.Errorf("shard 1 has %d URLs, want %d", got, maxURLsPerShard)
+	}
+	if got := len(readShardURLs(t, shards[1].path)); got != 1 {
+		t.Errorf("shard 2 has %d URLs, want 1", got)
+	}
+}
+
+func TestWriteShardsSplitsOnByteSize(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "sitemap.xml")
+
+	// Each record's marshaled size is ~40% of the 50 MiB shard cap, so
+	// two fit in a shard but a third pushes it over.
+	fill := strings.Repeat("a", int(maxBytesPerShard*2/5))
+	records := []urlRecord{
+		{Loc: "https://example.com/1/" + fill},
+		{Loc: "https://example.com/2/" + fill},
+		{Loc: "https://example.com/3/" + fill},
+	}
+
+	shards, err := writeShards(base, records)
+	if err != nil {
+		t.Fatalf("writeShards: %v", err)
+	}
+	if len(shards) != 2 {
+		t.Fatalf("got %d shards, want 2", len(shards))
+	}
+	if got := len(readShardURLs(t, shards[0].path)); got != 2 {
+		t.Errorf("shard 1 has %d URLs, want 2", got)
+	}
+	if got := len(readShardURLs(t, shards[1].path)); got != 1 {
+		t.Errorf("shard 2 has %d URLs, want 1", got)
+	}
+}
+
+func TestWriteShardsEmptyProducesOneShard(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "sitemap.xml")
+
+	shards, err := writeShards(base, nil)
+	if err != nil {
+		t.Fatalf("writeShards: %v", err)
+	}
+	if len(shards) != 1 {
+		t.Fatalf("got %d shards, want 1", len(shards))
+	}
+	if got := len(readShardURLs(t, shards[0].path)); got != 0 {
+		t.Errorf("empty shard has %d URLs, want 0", got)
+	}
+}
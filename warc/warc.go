@@ -0,0 +1,230 @@
+// Package warc writes crawl output in the WARC/1.1 format
+// (https://iipc.github.io/warc-specifications/specifications/warc-format/warc-1.1/),
+// gzip-compressed and split into size-capped segments.
+package warc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Writer appends request/response record pairs to a sequence of
+// gzipped WARC files, rolling over to a new segment before a record
+// would take the uncompressed bytes written to the current segment
+// past maxSize.
+//
+// A Writer is safe for concurrent use: callers in the worker pool each
+// hold their own URL but share one Writer, so all record writes are
+// serialized under mu.
+type Writer struct {
+	basePath string // e.g. "crawl.warc.gz"
+	maxSize  int64  // rollover threshold in bytes, 0 means no limit
+
+	mu                  sync.Mutex
+	segment             int
+	written             int64
+	needsInfo           bool
+	software, operator string
+	file                *os.File
+	gz                  *gzip.Writer
+}
+
+// NewWriter creates a Writer that writes to basePath (and, after
+// rollover, basePath with a "-NNNNN" suffix inserted before the
+// extension). maxSize <= 0 disables rollover.
+//
+// If basePath (or any of its numbered segments) already exists - e.g.
+// a -resume run reopening the WARC output from an interrupted crawl -
+// NewWriter starts at the first segment number that doesn't exist yet,
+// so the prior run's records are never truncated.
+func NewWriter(basePath string, maxSize int64) (*Writer, error) {
+	w := &Writer{basePath: basePath, maxSize: maxSize, segment: firstUnusedSegment(basePath)}
+	if err := w.openSegment(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// firstUnusedSegment returns the lowest segment number (starting at 1)
+// whose path doesn't already exist on disk.
+func firstUnusedSegment(basePath string) int {
+	segment := 1
+	for {
+		if _, err := os.Stat(segmentPathFor(basePath, segment)); os.IsNotExist(err) {
+			return segment
+		}
+		segment++
+	}
+}
+
+func segmentPathFor(basePath string, segment int) string {
+	if segment == 1 {
+		return basePath
+	}
+	dir, base := filepath.Split(basePath)
+	base = strings.TrimSuffix(base, ".gz")
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	return filepath.Join(dir, fmt.Sprintf("%s-%05d%s.gz", stem, segment, ext))
+}
+
+func (w *Writer) segmentPath() string {
+	return segmentPathFor(w.basePath, w.segment)
+}
+
+func (w *Writer) openSegment() error {
+	f, err := os.Create(w.segmentPath())
+	if err != nil {
+		return err
+	}
+	w.file = f
+	w.gz = gzip.NewWriter(f)
+	w.written = 0
+	w.needsInfo = true
+	return nil
+}
+
+// rollIfNeeded closes and reopens the segment if writing next more
+// bytes would exceed maxSize, re-emitting the warcinfo record into the
+// new segment so every segment is self-describing. Callers must hold mu.
+func (w *Writer) rollIfNeeded(next int64) error {
+	if w.maxSize <= 0 || w.written+next <= w.maxSize {
+		return nil
+	}
+	if err := w.closeSegment(); err != nil {
+		return err
+	}
+	w.segment++
+	if err := w.openSegment(); err != nil {
+		return err
+	}
+	return w.writeInfoLocked()
+}
+
+func (w *Writer) closeSegment() error {
+	if err := w.gz.Close(); err != nil {
+		return err
+	}
+	return w.file.Close()
+}
+
+// Close flushes and closes the current segment.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.closeSegment()
+}
+
+// WriteInfo writes a warcinfo record describing this crawl, if one
+// hasn't already been written to the current segment. Call it before
+// the first WriteRequest/WriteResponse of a run. The writer remembers
+// software/operator so later rollovers can re-emit the record on their
+// own, without requiring the caller to call WriteInfo again.
+func (w *Writer) WriteInfo(software, operator string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.software, w.operator = software, operator
+	return w.writeInfoLocked()
+}
+
+// writeInfoLocked writes the warcinfo record for the current segment
+// using the remembered software/operator. Callers must hold mu.
+func (w *Writer) writeInfoLocked() error {
+	if !w.needsInfo || w.software == "" {
+		return nil
+	}
+	block := fmt.Sprintf("software: %s\r\noperator: %s\r\nformat: WARC File Format 1.1\r\n", w.software, w.operator)
+	rec := buildRecord("warcinfo", "", []byte(block), "application/warc-fields")
+	if _, err := w.gz.Write(rec); err != nil {
+		return err
+	}
+	w.written += int64(len(rec))
+	w.needsInfo = false
+	return nil
+}
+
+// WriteRequest writes a request record for targetURI, whose block is
+// the raw HTTP request line and headers.
+func (w *Writer) WriteRequest(targetURI string, rawRequest []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	rec := buildRecord("request", targetURI, rawRequest, "application/http; msgtype=request")
+	if err := w.rollIfNeeded(int64(len(rec))); err != nil {
+		return err
+	}
+	if _, err := w.gz.Write(rec); err != nil {
+		return err
+	}
+	w.written += int64(len(rec))
+	return nil
+}
+
+// WriteResponse writes a response record for targetURI. headers and the
+// whole of body are copied into one in-memory block before the record
+// is written, so the full response is held in memory even when body is
+// read from a file; the body is not streamed into the WARC output.
+func (w *Writer) WriteResponse(targetURI string, headers []byte, body io.Reader) error {
+	var block bytes.Buffer
+	block.Write(headers)
+	if _, err := io.Copy(&block, body); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	rec := buildRecord("response", targetURI, block.Bytes(), "application/http; msgtype=response")
+	if err := w.rollIfNeeded(int64(len(rec))); err != nil {
+		return err
+	}
+	if _, err := w.gz.Write(rec); err != nil {
+		return err
+	}
+	w.written += int64(len(rec))
+	return nil
+}
+
+// buildRecord assembles a complete WARC record (header block plus
+// payload block plus the trailing CRLFCRLF) as described in section 4
+// of the WARC 1.1 spec.
+func buildRecord(warcType, targetURI string, block []byte, contentType string) []byte {
+	var h bytes.Buffer
+	h.WriteString("WARC/1.1\r\n")
+	fmt.Fprintf(&h, "WARC-Type: %s\r\n", warcType)
+	fmt.Fprintf(&h, "WARC-Date: %s\r\n", time.Now().UTC().Format(time.RFC3339))
+	fmt.Fprintf(&h, "WARC-Record-ID: <urn:uuid:%s>\r\n", newUUID())
+	if targetURI != "" {
+		fmt.Fprintf(&h, "WARC-Target-URI: %s\r\n", targetURI)
+	}
+	fmt.Fprintf(&h, "WARC-Block-Digest: sha1:%s\r\n", blockDigest(block))
+	fmt.Fprintf(&h, "Content-Type: %s\r\n", contentType)
+	fmt.Fprintf(&h, "Content-Length: %d\r\n", len(block))
+	h.WriteString("\r\n")
+	h.Write(block)
+	h.WriteString("\r\n\r\n")
+	return h.Bytes()
+}
+
+func blockDigest(block []byte) string {
+	sum := sha1.Sum(block)
+	return base32.StdEncoding.EncodeToString(sum[:])
+}
+
+// newUUID returns a random (version 4) UUID string.
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err) // crypto/rand failing is unrecoverable
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,113 @@
+package warc
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readAllRecords gunzips path (one segment file; rollover writes later
+// records to a new segment file) and returns its raw WARC text.
+func readAllRecords(t *testing.T, path string) string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("opening %s: %v", path, err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	defer gz.Close()
+
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("reading gunzipped content: %v", err)
+	}
+	return string(data)
+}
+
+func TestWriterWritesRequestAndResponseRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crawl.warc.gz")
+
+	w, err := NewWriter(path, 0)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	if err := w.WriteInfo("sitemap-generator-test", "tester"); err != nil {
+		t.Fatalf("WriteInfo: %v", err)
+	}
+	if err := w.WriteRequest("https://example.com/", []byte("GET / HTTP/1.1\r\n\r\n")); err != nil {
+		t.Fatalf("WriteRequest: %v", err)
+	}
+	if err := w.WriteResponse("https://example.com/", []byte("HTTP/1.1 200 OK\r\n\r\n"), strings.NewReader("hello")); err != nil {
+		t.Fatalf("WriteResponse: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	content := readAllRecords(t, path)
+	for _, want := range []string{"WARC-Type: warcinfo", "WARC-Type: request", "WARC-Type: response", "hello"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("output missing %q", want)
+		}
+	}
+}
+
+// TestNewWriterDoesNotTruncateExistingSegment simulates reopening the
+// WARC writer on a -resume run: a prior run's segment file already has
+// records in it, and a new Writer for the same basePath must not
+// truncate them.
+func TestNewWriterDoesNotTruncateExistingSegment(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crawl.warc.gz")
+
+	first, err := NewWriter(path, 0)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	if err := first.WriteRequest("https://example.com/first", []byte("GET /first HTTP/1.1\r\n\r\n")); err != nil {
+		t.Fatalf("WriteRequest: %v", err)
+	}
+	if err := first.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading segment 1 after first run: %v", err)
+	}
+
+	resumed, err := NewWriter(path, 0)
+	if err != nil {
+		t.Fatalf("NewWriter on resume: %v", err)
+	}
+	if err := resumed.WriteRequest("https://example.com/second", []byte("GET /second HTTP/1.1\r\n\r\n")); err != nil {
+		t.Fatalf("WriteRequest: %v", err)
+	}
+	if err := resumed.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading segment 1 after resume: %v", err)
+	}
+	if string(before) != string(after) {
+		t.Fatal("resuming NewWriter truncated or modified the first run's segment 1")
+	}
+
+	secondSegment := segmentPathFor(path, 2)
+	if _, err := os.Stat(secondSegment); err != nil {
+		t.Fatalf("resumed writer should have created a new segment at %s: %v", secondSegment, err)
+	}
+	content := readAllRecords(t, secondSegment)
+	if !strings.Contains(content, "/second") {
+		t.Errorf("new segment missing the resumed run's record: %q", content)
+	}
+}